@@ -0,0 +1,330 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// Retrier decides, attempt by attempt, whether a client may even contact the
+// server and what to do once it has. It generalizes Strategy so adaptive,
+// stateful policies (circuit breakers, retry budgets, concurrency limiters)
+// can sit in the same clientLoop as the original open-loop strategies.
+type Retrier interface {
+	// Allow is checked before every attempt. Returning false skips the
+	// server call entirely (e.g. an open circuit breaker, an exhausted
+	// retry budget) and sleeps for the given duration before asking again.
+	Allow() (allow bool, wait time.Duration)
+	// Next is called after an attempt that Allow permitted. ok reports
+	// whether the server accepted the request, latency is how long the
+	// attempt took, and retryAfter is the server's Retry-After hint (zero
+	// if the server gave none, meaningless when ok is true). It returns
+	// how long to sleep before the next attempt, and whether the client
+	// should give up.
+	Next(ok bool, latency, retryAfter time.Duration) (sleep time.Duration, giveUp bool)
+}
+
+// strategyRetrier adapts a stateless, open-loop Strategy into a Retrier so
+// it can run through the same clientLoop as the adaptive policies below. It
+// never withholds an attempt and never gives up, matching the original
+// simulator's behavior.
+type strategyRetrier struct {
+	strategy  Strategy
+	rng       *rand.Rand
+	attempt   int
+	prevDelay time.Duration
+}
+
+func newStrategyRetrier(strategy Strategy, rng *rand.Rand) *strategyRetrier {
+	return &strategyRetrier{strategy: strategy, rng: rng, prevDelay: baseSleep}
+}
+
+func (r *strategyRetrier) Allow() (bool, time.Duration) {
+	return true, 0
+}
+
+func (r *strategyRetrier) Next(ok bool, _, _ time.Duration) (time.Duration, bool) {
+	if ok {
+		return 0, false
+	}
+	delay := r.strategy(r.rng, r.attempt, r.prevDelay)
+	r.prevDelay = delay
+	r.attempt++
+	return delay, false
+}
+
+// breakerState is a client's view of its own circuit breaker.
+type breakerState int
+
+const (
+	breakerClosed breakerState = iota
+	breakerOpen
+	breakerHalfOpen
+)
+
+// circuitBreakerRetrier trips open after FailureThreshold consecutive
+// failures and stops contacting the server until OpenFor has elapsed, then
+// lets exactly one probe attempt through (half-open) to decide whether to
+// close again or re-open. Sleep timing between attempts is delegated to an
+// inner Retrier so a breaker can wrap any backoff strategy.
+type circuitBreakerRetrier struct {
+	inner            Retrier
+	metrics          *Metrics
+	failureThreshold int
+	openFor          time.Duration
+
+	state            breakerState
+	consecutiveFails int
+	openedAt         time.Time
+}
+
+func newCircuitBreakerRetrier(inner Retrier, failureThreshold int, openFor time.Duration, metrics *Metrics) *circuitBreakerRetrier {
+	return &circuitBreakerRetrier{inner: inner, failureThreshold: failureThreshold, openFor: openFor, metrics: metrics}
+}
+
+func (b *circuitBreakerRetrier) Allow() (bool, time.Duration) {
+	if b.state != breakerOpen {
+		return true, 0
+	}
+	if time.Since(b.openedAt) < b.openFor {
+		return false, b.openFor - time.Since(b.openedAt)
+	}
+	b.state = breakerHalfOpen
+	return true, 0
+}
+
+func (b *circuitBreakerRetrier) Next(ok bool, latency, retryAfter time.Duration) (time.Duration, bool) {
+	sleep, giveUp := b.inner.Next(ok, latency, retryAfter)
+
+	if ok {
+		b.consecutiveFails = 0
+		b.state = breakerClosed
+		return sleep, giveUp
+	}
+
+	if b.state == breakerHalfOpen {
+		b.state = breakerOpen
+		b.openedAt = time.Now()
+		return sleep, giveUp
+	}
+
+	b.consecutiveFails++
+	if b.state == breakerClosed && b.consecutiveFails >= b.failureThreshold {
+		b.state = breakerOpen
+		b.openedAt = time.Now()
+		b.metrics.RecordBreakerTrip()
+	}
+	return sleep, giveUp
+}
+
+// aimdRetrier keeps a per-client retry budget that additively recovers on
+// success and is halved (multiplicatively decreased) on rejection. When the
+// budget drops below one token, the client backs off instead of contacting
+// the server, the way a sender stops sending under AIMD congestion
+// control - except for an occasional probe attempt, throttled to one per
+// probeInterval, since budget can only grow again via a successful Next(),
+// which requires an Allow() that let the attempt through in the first
+// place. Without that trickle, a budget that ever dips below one token
+// would deny every future attempt forever.
+type aimdRetrier struct {
+	inner             Retrier
+	metrics           *Metrics
+	budget            float64
+	maxBudget         float64
+	additiveInc       float64
+	multiplicativeDec float64
+
+	probeInterval time.Duration
+	lastProbe     time.Time
+}
+
+func newAIMDRetrier(inner Retrier, maxBudget, additiveInc, multiplicativeDec float64, metrics *Metrics) *aimdRetrier {
+	return &aimdRetrier{
+		inner:             inner,
+		metrics:           metrics,
+		budget:            maxBudget,
+		maxBudget:         maxBudget,
+		additiveInc:       additiveInc,
+		multiplicativeDec: multiplicativeDec,
+		probeInterval:     baseSleep,
+	}
+}
+
+func (a *aimdRetrier) Allow() (bool, time.Duration) {
+	if a.budget >= 1 {
+		return true, 0
+	}
+	if time.Since(a.lastProbe) >= a.probeInterval {
+		a.lastProbe = time.Now()
+		return true, 0
+	}
+	a.metrics.RecordBudgetDenial()
+	return false, a.probeInterval
+}
+
+func (a *aimdRetrier) Next(ok bool, latency, retryAfter time.Duration) (time.Duration, bool) {
+	sleep, giveUp := a.inner.Next(ok, latency, retryAfter)
+	if ok {
+		a.budget += a.additiveInc
+	} else {
+		a.budget *= a.multiplicativeDec
+	}
+	if a.budget > a.maxBudget {
+		a.budget = a.maxBudget
+	}
+	if a.budget < 0 {
+		a.budget = 0
+	}
+	return sleep, giveUp
+}
+
+// AdaptiveLimiter is a shared, Little's-Law-based concurrency limiter in
+// the spirit of Netflix's concurrency-limits gradient algorithm: after each
+// attempt the limit becomes limit * (minRTT / latency) + 1, so it is nudged
+// down when latency grows relative to the best latency observed (a sign of
+// queueing) and creeps back up by one otherwise; a rejection halves it
+// instead. Throughput itself is not measured. It is shared across every client using it, since the limit
+// is a property of the system's capacity, not of any one client.
+type AdaptiveLimiter struct {
+	mu       sync.Mutex
+	limit    float64
+	minLimit float64
+	maxLimit float64
+	inFlight int
+	minRTT   time.Duration
+	metrics  *Metrics
+}
+
+func NewAdaptiveLimiter(initialLimit, minLimit, maxLimit float64, metrics *Metrics) *AdaptiveLimiter {
+	return &AdaptiveLimiter{limit: initialLimit, minLimit: minLimit, maxLimit: maxLimit, metrics: metrics}
+}
+
+func (l *AdaptiveLimiter) acquire() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if float64(l.inFlight) >= l.limit {
+		return false
+	}
+	l.inFlight++
+	return true
+}
+
+func (l *AdaptiveLimiter) release(latency time.Duration, rejected bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.inFlight--
+
+	if l.minRTT == 0 || latency < l.minRTT {
+		l.minRTT = latency
+	}
+
+	gradient := 1.0
+	if latency > 0 {
+		gradient = float64(l.minRTT) / float64(latency)
+		if gradient > 1 {
+			gradient = 1
+		}
+	}
+	if rejected {
+		gradient = 0.5
+	}
+
+	newLimit := l.limit*gradient + 1
+	if newLimit > l.maxLimit {
+		newLimit = l.maxLimit
+	}
+	if newLimit < l.minLimit {
+		newLimit = l.minLimit
+	}
+	l.limit = newLimit
+	l.metrics.RecordLimitSample(int(l.limit))
+}
+
+// adaptiveConcurrencyRetrier gates attempts on a shared AdaptiveLimiter and
+// delegates sleep timing between attempts to an inner Retrier.
+type adaptiveConcurrencyRetrier struct {
+	inner   Retrier
+	limiter *AdaptiveLimiter
+}
+
+func newAdaptiveConcurrencyRetrier(inner Retrier, limiter *AdaptiveLimiter) *adaptiveConcurrencyRetrier {
+	return &adaptiveConcurrencyRetrier{inner: inner, limiter: limiter}
+}
+
+func (a *adaptiveConcurrencyRetrier) Allow() (bool, time.Duration) {
+	if !a.limiter.acquire() {
+		return false, baseSleep
+	}
+	return true, 0
+}
+
+func (a *adaptiveConcurrencyRetrier) Next(ok bool, latency, retryAfter time.Duration) (time.Duration, bool) {
+	a.limiter.release(latency, !ok)
+	return a.inner.Next(ok, latency, retryAfter)
+}
+
+// retrierParams bundles the CLI-supplied tuning knobs for the adaptive
+// strategies, so buildRetrierFactory doesn't need one parameter per flag.
+type retrierParams struct {
+	seed int64 // run seed; each client derives its own rng from this plus its index
+
+	breakerThreshold   int
+	breakerOpenFor     time.Duration
+	aimdMaxBudget      float64
+	aimdAdditive       float64
+	aimdMultiplicative float64
+
+	concurrencyInitialLimit float64
+	concurrencyMinLimit     float64
+	concurrencyMaxLimit     float64
+}
+
+// buildRetrierFactory returns a constructor that produces a fresh Retrier
+// per client for the strategy named by flag value name, given the client's
+// index (used to derive that client's own rng so a seeded run is
+// reproducible regardless of goroutine scheduling). Open-loop strategies
+// (constant, backoff, jitter, decorrelated) get an independent
+// strategyRetrier each; adaptive strategies wrap decorrelatedJitter as
+// their inner backoff and, where the policy is system-wide rather than
+// per-client (adaptive-concurrency), share a single piece of state across
+// every client produced by the factory.
+func buildRetrierFactory(name string, metrics *Metrics, p retrierParams) (func(clientIndex int) Retrier, error) {
+	openLoop := map[string]Strategy{
+		"constant":     constantRetry,
+		"backoff":      exponentialBackoff,
+		"jitter":       fullJitter,
+		"decorrelated": decorrelatedJitter,
+	}
+
+	if strategy, ok := openLoop[name]; ok {
+		return func(clientIndex int) Retrier {
+			return newStrategyRetrier(strategy, deriveClientRand(p.seed, clientIndex))
+		}, nil
+	}
+
+	switch name {
+	case "breaker":
+		return func(clientIndex int) Retrier {
+			rng := deriveClientRand(p.seed, clientIndex)
+			return newCircuitBreakerRetrier(newStrategyRetrier(decorrelatedJitter, rng), p.breakerThreshold, p.breakerOpenFor, metrics)
+		}, nil
+	case "aimd":
+		return func(clientIndex int) Retrier {
+			rng := deriveClientRand(p.seed, clientIndex)
+			return newAIMDRetrier(newStrategyRetrier(decorrelatedJitter, rng), p.aimdMaxBudget, p.aimdAdditive, p.aimdMultiplicative, metrics)
+		}, nil
+	case "adaptive-concurrency":
+		limiter := NewAdaptiveLimiter(p.concurrencyInitialLimit, p.concurrencyMinLimit, p.concurrencyMaxLimit, metrics)
+		return func(clientIndex int) Retrier {
+			rng := deriveClientRand(p.seed, clientIndex)
+			return newAdaptiveConcurrencyRetrier(newStrategyRetrier(decorrelatedJitter, rng), limiter)
+		}, nil
+	case "server-hinted":
+		return func(clientIndex int) Retrier {
+			return newServerHintedRetrier(deriveClientRand(p.seed, clientIndex))
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown strategy %q. Use: constant, backoff, jitter, decorrelated, breaker, aimd, adaptive-concurrency, server-hinted", name)
+	}
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"time"
+)
+
+// arrivalRng backs the arrival processes below, which need their own
+// *rand.Rand (e.g. so a Zipf distribution can be built from it). Arrival
+// schedules are computed once, up front, before any client goroutines
+// start, so a single shared source is safe here - unlike the retry
+// strategies, which run concurrently across client goroutines and so each
+// get their own rng (see deriveClientRand). It starts out time-seeded but
+// main reseeds it deterministically from -seed.
+var arrivalRng = rand.New(rand.NewSource(time.Now().UnixNano()))
+
+// seedArrival reseeds the arrival processes' RNG, used to make a simulation
+// run reproducible.
+func seedArrival(seed int64) {
+	arrivalRng = rand.New(rand.NewSource(seed))
+}
+
+// ArrivalProcess decides when each simulated client starts its clientLoop,
+// relative to the beginning of the simulation.
+type ArrivalProcess interface {
+	// Schedule returns, for n clients, the delay from simulation start at
+	// which client i should begin. The returned slice has length n.
+	Schedule(n int) []time.Duration
+}
+
+// SimultaneousArrival starts every client at once, modeling a synchronized
+// thundering herd. This reproduces the simulator's original behavior.
+type SimultaneousArrival struct{}
+
+func (SimultaneousArrival) Schedule(n int) []time.Duration {
+	return make([]time.Duration, n)
+}
+
+// PoissonArrival spaces client starts using exponential inter-arrival times,
+// the standard model for independent clients arriving at a steady average
+// rate (clients/sec).
+type PoissonArrival struct {
+	Rate float64 // average clients per second
+}
+
+func (p PoissonArrival) Schedule(n int) []time.Duration {
+	offsets := make([]time.Duration, n)
+	var t time.Duration
+	for i := 0; i < n; i++ {
+		t += exponentialDelay(p.Rate)
+		offsets[i] = t
+	}
+	return offsets
+}
+
+// UniformArrival spreads client starts independently and uniformly across
+// the window it would take to admit all clients at the given average rate.
+type UniformArrival struct {
+	Rate float64 // average clients per second
+}
+
+func (u UniformArrival) Schedule(n int) []time.Duration {
+	window := float64(n) / u.Rate
+	offsets := make([]time.Duration, n)
+	for i := 0; i < n; i++ {
+		offsets[i] = time.Duration(arrivalRng.Float64() * window * float64(time.Second))
+	}
+	sort.Slice(offsets, func(i, j int) bool { return offsets[i] < offsets[j] })
+	return offsets
+}
+
+// ZipfianArrival draws a rank per client from a Zipf distribution and maps
+// low ranks to near-simultaneous arrival. Because Zipf concentrates mass on
+// the low ranks, a small number of clients end up clustered together and
+// dominate the retry storm, while the long tail trickles in far later.
+type ZipfianArrival struct {
+	Rate float64 // controls the spacing between ranks (clients/sec)
+	S    float64 // Zipf s parameter (> 1); higher skews harder toward rank 0
+	V    float64 // Zipf v parameter (>= 1)
+	N    uint64  // number of distinct ranks
+}
+
+func (z ZipfianArrival) Schedule(n int) []time.Duration {
+	gap := time.Duration(float64(time.Second) / z.Rate)
+	zipf := rand.NewZipf(arrivalRng, z.S, z.V, z.N)
+	offsets := make([]time.Duration, n)
+	for i := 0; i < n; i++ {
+		rank := zipf.Uint64()
+		offsets[i] = time.Duration(rank) * gap
+	}
+	return offsets
+}
+
+// BurstyArrival alternates "on" windows, where clients arrive as a Poisson
+// process at Rate, with quiet "off" windows where nobody arrives.
+type BurstyArrival struct {
+	Rate  float64 // clients per second during an on window
+	OnFor time.Duration
+	OffFor time.Duration
+}
+
+func (b BurstyArrival) Schedule(n int) []time.Duration {
+	offsets := make([]time.Duration, n)
+	var t time.Duration
+	cycle := b.OnFor + b.OffFor
+	for i := 0; i < n; i++ {
+		t += exponentialDelay(b.Rate)
+		// Fold t back into whichever "on" window it lands in by pushing it
+		// past any "off" windows it would otherwise fall inside.
+		phase := t % cycle
+		if phase >= b.OnFor {
+			t += cycle - phase
+		}
+		offsets[i] = t
+	}
+	return offsets
+}
+
+// exponentialDelay draws a single exponential inter-arrival time for a
+// Poisson process with the given average rate (events/sec).
+func exponentialDelay(rate float64) time.Duration {
+	return time.Duration(arrivalRng.ExpFloat64() / rate * float64(time.Second))
+}
+
+// buildArrivalProcess constructs the ArrivalProcess named by flag value
+// name, using the given CLI-supplied parameters.
+func buildArrivalProcess(name string, rate, zipfS, zipfV float64, burstOn, burstOff time.Duration, numClients int) (ArrivalProcess, error) {
+	switch name {
+	case "simultaneous":
+		return SimultaneousArrival{}, nil
+	case "poisson":
+		return PoissonArrival{Rate: rate}, nil
+	case "uniform":
+		return UniformArrival{Rate: rate}, nil
+	case "zipfian":
+		// rand.NewZipf silently returns nil outside these bounds, which
+		// would panic on the first Uint64() call rather than failing here.
+		if zipfS <= 1 {
+			return nil, fmt.Errorf("zipfian arrival requires -zipf-s > 1, got %v", zipfS)
+		}
+		if zipfV < 1 {
+			return nil, fmt.Errorf("zipfian arrival requires -zipf-v >= 1, got %v", zipfV)
+		}
+		return ZipfianArrival{Rate: rate, S: zipfS, V: zipfV, N: uint64(numClients)}, nil
+	case "bursty":
+		return BurstyArrival{Rate: rate, OnFor: burstOn, OffFor: burstOff}, nil
+	default:
+		return nil, fmt.Errorf("unknown arrival process %q. Use: simultaneous, poisson, uniform, zipfian, bursty", name)
+	}
+}
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// seedRand reseeds arrival sampling, so a run (or one leg of a -compare
+// run) sees the same client arrival schedule from a given seed. Retry
+// strategies don't need reseeding here: each client derives its own rng
+// from the seed and its client index (see deriveClientRand), rather than
+// drawing from a shared source that this function would otherwise need to
+// seed.
+func seedRand(seed int64) {
+	seedArrival(seed)
+}
+
+// simConfig bundles everything needed to run one simulation, so -compare
+// can run the same scenario with only the strategy swapped out.
+type simConfig struct {
+	numClients     int
+	serverCapacity int
+	downDuration   time.Duration
+	seed           int64
+
+	buildArrival func() (ArrivalProcess, error)
+	buildModel   func() (serverModel, error)
+}
+
+// runOne runs a single simulation leg for the named strategy against a
+// fresh Server and arrival process, reseeded from cfg.seed so every
+// strategy in a -compare run sees the same outage and the same clients.
+func runOne(strategyName string, cfg simConfig, p retrierParams) (*Server, *Metrics, error) {
+	seedRand(cfg.seed)
+	p.seed = cfg.seed
+
+	arrival, err := cfg.buildArrival()
+	if err != nil {
+		return nil, nil, err
+	}
+	model, err := cfg.buildModel()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	srv := NewServer(cfg.serverCapacity, cfg.downDuration)
+	srv.model = model
+
+	metrics := NewMetrics()
+	newRetrier, err := buildRetrierFactory(strategyName, metrics, p)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	runSimulation(cfg.numClients, srv, newRetrier, arrival, metrics)
+	return srv, metrics, nil
+}
+
+// compareResult is one row of a -compare report.
+type compareResult struct {
+	Strategy      string  `json:"strategy"`
+	TimeToStable  int     `json:"time_to_stable_sec"` // -1 if not observed within the window
+	PeakOvershoot int     `json:"peak_overshoot"`
+	WastedPct     float64 `json:"wasted_pct"`
+	P50Ms         float64 `json:"p50_ms"`
+	P95Ms         float64 `json:"p95_ms"`
+	P99Ms         float64 `json:"p99_ms"`
+	P999Ms        float64 `json:"p999_ms"`
+}
+
+func newCompareResult(name string, stats summaryStats) compareResult {
+	ms := func(d time.Duration) float64 { return float64(d) / float64(time.Millisecond) }
+	return compareResult{
+		Strategy:      name,
+		TimeToStable:  stats.recoveryTime,
+		PeakOvershoot: stats.peakOvershoot,
+		WastedPct:     stats.wastedPct(),
+		P50Ms:         ms(stats.p50),
+		P95Ms:         ms(stats.p95),
+		P99Ms:         ms(stats.p99),
+		P999Ms:        ms(stats.p999),
+	}
+}
+
+// runCompare runs every strategy in strategyNames against the same
+// scenario and returns one compareResult per strategy, in the order given.
+func runCompare(strategyNames []string, cfg simConfig, p retrierParams) ([]compareResult, error) {
+	results := make([]compareResult, 0, len(strategyNames))
+	for _, name := range strategyNames {
+		srv, metrics, err := runOne(name, cfg, p)
+		if err != nil {
+			return nil, fmt.Errorf("strategy %q: %w", name, err)
+		}
+		results = append(results, newCompareResult(name, computeSummary(srv, metrics)))
+	}
+	return results, nil
+}
+
+func printCompareTable(results []compareResult) {
+	fmt.Println()
+	fmt.Printf("  %-20s %12s %10s %9s %8s %8s %8s %8s\n",
+		"Strategy", "Time-stable", "Overshoot", "Wasted%", "p50", "p95", "p99", "p999")
+	for _, r := range results {
+		stable := "> 60s"
+		if r.TimeToStable >= 0 {
+			stable = fmt.Sprintf("%ds", r.TimeToStable)
+		}
+		fmt.Printf("  %-20s %12s %10d %8.1f%% %7.1fms %7.1fms %7.1fms %7.1fms\n",
+			r.Strategy, stable, r.PeakOvershoot, r.WastedPct, r.P50Ms, r.P95Ms, r.P99Ms, r.P999Ms)
+	}
+	fmt.Println()
+}
+
+func writeCompareJSON(w *os.File, results []compareResult) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(results)
+}
+
+func writeCompareCSV(w *os.File, results []compareResult) error {
+	cw := csv.NewWriter(w)
+	defer cw.Flush()
+
+	header := []string{"strategy", "time_to_stable_sec", "peak_overshoot", "wasted_pct", "p50_ms", "p95_ms", "p99_ms", "p999_ms"}
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+	for _, r := range results {
+		row := []string{
+			r.Strategy,
+			fmt.Sprintf("%d", r.TimeToStable),
+			fmt.Sprintf("%d", r.PeakOvershoot),
+			fmt.Sprintf("%.2f", r.WastedPct),
+			fmt.Sprintf("%.2f", r.P50Ms),
+			fmt.Sprintf("%.2f", r.P95Ms),
+			fmt.Sprintf("%.2f", r.P99Ms),
+			fmt.Sprintf("%.2f", r.P999Ms),
+		}
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeComparePrometheus(w *os.File, results []compareResult) error {
+	var b strings.Builder
+	b.WriteString("# HELP retry_sim_time_to_stable_seconds Seconds after the outage before no requests are rejected.\n")
+	b.WriteString("# TYPE retry_sim_time_to_stable_seconds gauge\n")
+	for _, r := range results {
+		if r.TimeToStable >= 0 {
+			fmt.Fprintf(&b, "retry_sim_time_to_stable_seconds{strategy=%q} %d\n", r.Strategy, r.TimeToStable)
+		}
+	}
+	b.WriteString("# HELP retry_sim_peak_overshoot Peak requests/sec above server capacity during recovery.\n")
+	b.WriteString("# TYPE retry_sim_peak_overshoot gauge\n")
+	for _, r := range results {
+		fmt.Fprintf(&b, "retry_sim_peak_overshoot{strategy=%q} %d\n", r.Strategy, r.PeakOvershoot)
+	}
+	b.WriteString("# HELP retry_sim_wasted_ratio Fraction of requests that were retries wasted on a rejected attempt.\n")
+	b.WriteString("# TYPE retry_sim_wasted_ratio gauge\n")
+	for _, r := range results {
+		fmt.Fprintf(&b, "retry_sim_wasted_ratio{strategy=%q} %.4f\n", r.Strategy, r.WastedPct/100)
+	}
+	b.WriteString("# HELP retry_sim_latency_seconds Client-observed latency quantiles.\n")
+	b.WriteString("# TYPE retry_sim_latency_seconds summary\n")
+	for _, r := range results {
+		fmt.Fprintf(&b, "retry_sim_latency_seconds{strategy=%q,quantile=\"0.5\"} %.6f\n", r.Strategy, r.P50Ms/1000)
+		fmt.Fprintf(&b, "retry_sim_latency_seconds{strategy=%q,quantile=\"0.95\"} %.6f\n", r.Strategy, r.P95Ms/1000)
+		fmt.Fprintf(&b, "retry_sim_latency_seconds{strategy=%q,quantile=\"0.99\"} %.6f\n", r.Strategy, r.P99Ms/1000)
+		fmt.Fprintf(&b, "retry_sim_latency_seconds{strategy=%q,quantile=\"0.999\"} %.6f\n", r.Strategy, r.P999Ms/1000)
+	}
+	_, err := w.WriteString(b.String())
+	return err
+}
+
+// writeCompareReport renders results in the given format ("table", "json",
+// "csv", or "prom") to stdout.
+func writeCompareReport(format string, results []compareResult) error {
+	switch format {
+	case "", "table":
+		printCompareTable(results)
+		return nil
+	case "json":
+		return writeCompareJSON(os.Stdout, results)
+	case "csv":
+		return writeCompareCSV(os.Stdout, results)
+	case "prom":
+		return writeComparePrometheus(os.Stdout, results)
+	default:
+		return fmt.Errorf("unknown format %q. Use: table, json, csv, prom", format)
+	}
+}
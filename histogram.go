@@ -0,0 +1,201 @@
+package main
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// quantileObjectives are the latency quantiles the simulator tracks via
+// streaming summaries, in the spirit of a Prometheus client library
+// Summary's objectives (e.g. {0.5: 0.05, 0.9: 0.01, 0.99: 0.001}).
+var quantileObjectives = []float64{0.5, 0.9, 0.95, 0.99, 0.999}
+
+// latencyHistogram is a fixed set of exponential buckets, Prometheus-style,
+// so a long-running simulation can track a latency distribution in O(1)
+// memory instead of retaining every observation.
+type latencyHistogram struct {
+	mu     sync.Mutex
+	bounds []time.Duration // upper bound of each bucket, ascending; observations above the last bound fall in an implicit +Inf bucket
+	counts []uint64
+	count  uint64
+	sum    time.Duration
+}
+
+// newLatencyHistogram builds buckets doubling from 1ms up to roughly 17s,
+// which comfortably spans this simulator's baseSleep..capSleep range plus
+// the multi-second queueing delays the server models can add.
+func newLatencyHistogram() *latencyHistogram {
+	const (
+		start  = time.Millisecond
+		factor = 2.0
+		n      = 15
+	)
+	bounds := make([]time.Duration, n)
+	v := float64(start)
+	for i := range bounds {
+		bounds[i] = time.Duration(v)
+		v *= factor
+	}
+	return &latencyHistogram{bounds: bounds, counts: make([]uint64, n+1)}
+}
+
+func (h *latencyHistogram) observe(d time.Duration) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.count++
+	h.sum += d
+	idx := sort.Search(len(h.bounds), func(i int) bool { return h.bounds[i] >= d })
+	h.counts[idx]++
+}
+
+// snapshot returns the bucket upper bounds and their counts as of now.
+// counts has one more entry than bounds: the last count is the implicit
+// +Inf bucket, which has no entry in bounds.
+func (h *latencyHistogram) snapshot() (bounds []time.Duration, counts []uint64) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	bounds = append(bounds, h.bounds...)
+	counts = append(counts, h.counts...)
+	return bounds, counts
+}
+
+// p2Quantile estimates a single quantile from a data stream in O(1) memory
+// using the P² (piecewise-parabolic) algorithm (Jain & Chlamtac, 1985),
+// rather than retaining every observation to sort and index into later.
+type p2Quantile struct {
+	p        float64
+	count    int
+	n        [5]int
+	nDesired [5]float64
+	dn       [5]float64
+	q        [5]float64
+}
+
+func newP2Quantile(p float64) *p2Quantile {
+	return &p2Quantile{
+		p:  p,
+		dn: [5]float64{0, p / 2, p, (1 + p) / 2, 1},
+	}
+}
+
+func (m *p2Quantile) observe(x float64) {
+	if m.count < 5 {
+		i := m.count
+		for i > 0 && m.q[i-1] > x {
+			m.q[i] = m.q[i-1]
+			i--
+		}
+		m.q[i] = x
+		m.count++
+		if m.count == 5 {
+			sort.Float64s(m.q[:])
+			for i := range m.n {
+				m.n[i] = i
+				m.nDesired[i] = 1 + 4*m.dn[i]
+			}
+		}
+		return
+	}
+
+	k := 0
+	switch {
+	case x < m.q[0]:
+		m.q[0] = x
+	case x >= m.q[4]:
+		m.q[4] = x
+		k = 3
+	default:
+		for i := 0; i < 4; i++ {
+			if m.q[i] <= x && x < m.q[i+1] {
+				k = i
+				break
+			}
+		}
+	}
+
+	for i := k + 1; i < 5; i++ {
+		m.n[i]++
+	}
+	for i := range m.nDesired {
+		m.nDesired[i] += m.dn[i]
+	}
+
+	for i := 1; i < 4; i++ {
+		d := m.nDesired[i] - float64(m.n[i])
+		if d >= 1 && m.n[i+1]-m.n[i] > 1 {
+			m.adjust(i, 1)
+		} else if d <= -1 && m.n[i-1]-m.n[i] < -1 {
+			m.adjust(i, -1)
+		}
+	}
+}
+
+func (m *p2Quantile) adjust(i, d int) {
+	qNew := m.parabolic(i, d)
+	if m.q[i-1] < qNew && qNew < m.q[i+1] {
+		m.q[i] = qNew
+	} else {
+		m.q[i] = m.linear(i, d)
+	}
+	m.n[i] += d
+}
+
+func (m *p2Quantile) parabolic(i, d int) float64 {
+	return m.q[i] + float64(d)/float64(m.n[i+1]-m.n[i-1])*
+		(float64(m.n[i]-m.n[i-1]+d)*(m.q[i+1]-m.q[i])/float64(m.n[i+1]-m.n[i])+
+			float64(m.n[i+1]-m.n[i]-d)*(m.q[i]-m.q[i-1])/float64(m.n[i]-m.n[i-1]))
+}
+
+func (m *p2Quantile) linear(i, d int) float64 {
+	return m.q[i] + float64(d)*(m.q[i+d]-m.q[i])/float64(m.n[i+d]-m.n[i])
+}
+
+func (m *p2Quantile) value() float64 {
+	if m.count == 0 {
+		return 0
+	}
+	if m.count < 5 {
+		sorted := append([]float64(nil), m.q[:m.count]...)
+		sort.Float64s(sorted)
+		idx := int(m.p * float64(len(sorted)-1))
+		return sorted[idx]
+	}
+	return m.q[2]
+}
+
+// QuantileSummary is a streaming, constant-memory latency quantile
+// estimator, one P² estimator per objective - the same role a Prometheus
+// client library Summary plays for a metric with configured objectives.
+type QuantileSummary struct {
+	mu         sync.Mutex
+	estimators map[float64]*p2Quantile
+}
+
+func NewQuantileSummary(objectives []float64) *QuantileSummary {
+	s := &QuantileSummary{estimators: make(map[float64]*p2Quantile, len(objectives))}
+	for _, o := range objectives {
+		s.estimators[o] = newP2Quantile(o)
+	}
+	return s
+}
+
+func (s *QuantileSummary) Observe(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, e := range s.estimators {
+		e.observe(float64(d))
+	}
+}
+
+// Quantile returns the current estimate for objective q, or 0 if q wasn't
+// one of the summary's configured objectives.
+func (s *QuantileSummary) Quantile(q float64) time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, ok := s.estimators[q]
+	if !ok {
+		return 0
+	}
+	return time.Duration(e.value())
+}
@@ -5,64 +5,28 @@ import (
 	"fmt"
 	"math"
 	"math/rand"
-	"sort"
+	"os"
 	"strings"
 	"sync"
 	"time"
 )
 
-// Server simulates a service with limited capacity.
-type Server struct {
-	mu       sync.Mutex
-	capacity int
-	downFor  time.Duration
-	start    time.Time
-	requests  map[int]int // second -> request count
-	accepted map[int]int // second -> accepted count
-}
-
-func NewServer(capacity int, downFor time.Duration) *Server {
-	return &Server{
-		capacity: capacity,
-		downFor:  downFor,
-		start:    time.Now(),
-		requests:  make(map[int]int),
-		accepted: make(map[int]int),
-	}
-}
-
-// Do attempts a request. Returns true if the server accepted it.
-func (s *Server) Do() bool {
-	s.mu.Lock()
-	defer s.mu.Unlock()
-
-	elapsed := time.Since(s.start)
-	sec := int(elapsed.Seconds())
-	s.requests[sec]++
-
-	if elapsed < s.downFor {
-		return false
-	}
-	if s.accepted[sec] >= s.capacity {
-		return false
-	}
-	s.accepted[sec]++
-	return true
-}
-
-// Strategy computes the next sleep duration given the attempt number and previous delay.
-type Strategy func(attempt int, prevDelay time.Duration) time.Duration
+// Strategy computes the next sleep duration given the attempt number and
+// previous delay. It takes its own *rand.Rand rather than drawing from
+// math/rand's global source, so a client's sequence of delays depends only
+// on its own rng, not on the order goroutines happen to interleave in.
+type Strategy func(rng *rand.Rand, attempt int, prevDelay time.Duration) time.Duration
 
 const (
 	baseSleep = 100 * time.Millisecond
 	capSleep  = 10 * time.Second
 )
 
-func constantRetry(_ int, _ time.Duration) time.Duration {
+func constantRetry(_ *rand.Rand, _ int, _ time.Duration) time.Duration {
 	return 1 * time.Millisecond
 }
 
-func exponentialBackoff(attempt int, _ time.Duration) time.Duration {
+func exponentialBackoff(_ *rand.Rand, attempt int, _ time.Duration) time.Duration {
 	d := time.Duration(float64(baseSleep) * math.Pow(2, float64(attempt)))
 	if d > capSleep {
 		d = capSleep
@@ -70,68 +34,188 @@ func exponentialBackoff(attempt int, _ time.Duration) time.Duration {
 	return d
 }
 
-func fullJitter(attempt int, _ time.Duration) time.Duration {
+func fullJitter(rng *rand.Rand, attempt int, _ time.Duration) time.Duration {
 	d := time.Duration(float64(baseSleep) * math.Pow(2, float64(attempt)))
 	if d > capSleep {
 		d = capSleep
 	}
-	return time.Duration(rand.Int63n(int64(d)))
+	return time.Duration(rng.Int63n(int64(d)))
 }
 
-func decorrelatedJitter(_ int, prev time.Duration) time.Duration {
+func decorrelatedJitter(rng *rand.Rand, _ int, prev time.Duration) time.Duration {
 	if prev < baseSleep {
 		prev = baseSleep
 	}
-	d := time.Duration(rand.Int63n(int64(prev)*3-int64(baseSleep))) + baseSleep
+	d := time.Duration(rng.Int63n(int64(prev)*3-int64(baseSleep))) + baseSleep
 	if d > capSleep {
 		d = capSleep
 	}
 	return d
 }
 
-// Metrics collected across all clients.
+// deriveClientRand returns an independent *rand.Rand for one client,
+// deterministically derived from the run seed and the client's index (a
+// boost::hash_combine-style mix so nearby indices don't produce
+// near-identical seeds). Giving every client its own source, rather than
+// letting every client draw from math/rand's shared global source, is what
+// makes each client's own sequence of retry delays reproducible from the
+// seed: with a shared source, the sequence each client draws from it
+// depends on however goroutines happen to be scheduled, not just the seed.
+// Request bucketing and measured latency still depend on real wall-clock
+// scheduling, so a seeded run's aggregate results (peak overshoot,
+// latency percentiles, etc.) are close but not bit-identical run to run.
+func deriveClientRand(seed int64, clientIndex int) *rand.Rand {
+	h := uint64(seed)
+	h ^= uint64(clientIndex) + 0x9e3779b97f4a7c15 + (h << 6) + (h >> 2)
+	return rand.New(rand.NewSource(int64(h)))
+}
+
+// Metrics collected across all clients. Latencies are tracked via a
+// bounded-memory histogram and streaming quantile summary rather than a
+// growing slice, so a long or large simulation doesn't retain every
+// observation just to report percentiles at the end.
 type Metrics struct {
 	mu            sync.Mutex
 	totalRequests int
 	wastedReqs    int
-	latencies     []time.Duration
+	served        int   // clients whose request eventually succeeded
+	giveUps       int   // clients that abandoned a request without success
+	breakerTrips  int   // times a closed circuit breaker tripped open (re-opening after a failed half-open probe is not counted)
+	budgetDenials int   // times an AIMD retry budget refused an attempt
+	limitSamples  []int // adaptive concurrency limiter's chosen limit, sampled over time
+
+	histogram *latencyHistogram
+	summary   *QuantileSummary
+	perSecond map[int]*QuantileSummary // second -> latency quantile summary for requests completed that second
+
+	inFlight    int
+	maxInFlight map[int]int // second -> peak concurrent in-flight attempts observed
+}
+
+func NewMetrics() *Metrics {
+	return &Metrics{
+		histogram:   newLatencyHistogram(),
+		summary:     NewQuantileSummary(quantileObjectives),
+		perSecond:   make(map[int]*QuantileSummary),
+		maxInFlight: make(map[int]int),
+	}
 }
 
-func (m *Metrics) Record(latency time.Duration, wasted int) {
+func (m *Metrics) Record(latency time.Duration, wasted, sec int) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 	m.totalRequests += wasted + 1
 	m.wastedReqs += wasted
-	m.latencies = append(m.latencies, latency)
+	m.served++
+	m.histogram.observe(latency)
+	m.summary.Observe(latency)
+	m.secondSummaryLocked(sec).Observe(latency)
 }
 
-func clientLoop(srv *Server, strategy Strategy, metrics *Metrics) {
+// secondSummaryLocked returns (creating if necessary) the quantile summary
+// for the given simulated second. Caller must hold m.mu.
+func (m *Metrics) secondSummaryLocked(sec int) *QuantileSummary {
+	s, ok := m.perSecond[sec]
+	if !ok {
+		s = NewQuantileSummary(quantileObjectives)
+		m.perSecond[sec] = s
+	}
+	return s
+}
+
+// BeginAttempt records an in-flight attempt starting in the given simulated
+// second, for the in-flight time series.
+func (m *Metrics) BeginAttempt(sec int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.inFlight++
+	if m.inFlight > m.maxInFlight[sec] {
+		m.maxInFlight[sec] = m.inFlight
+	}
+}
+
+// EndAttempt records an in-flight attempt finishing.
+func (m *Metrics) EndAttempt() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.inFlight--
+}
+
+// RecordGiveUp records a client that abandoned its request after the given
+// number of wasted attempts, without ever succeeding.
+func (m *Metrics) RecordGiveUp(wasted int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.totalRequests += wasted
+	m.wastedReqs += wasted
+	m.giveUps++
+}
+
+func (m *Metrics) RecordBreakerTrip() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.breakerTrips++
+}
+
+func (m *Metrics) RecordBudgetDenial() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.budgetDenials++
+}
+
+func (m *Metrics) RecordLimitSample(limit int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.limitSamples = append(m.limitSamples, limit)
+}
+
+func clientLoop(srv *Server, retrier Retrier, metrics *Metrics) {
 	start := time.Now()
 	attempt := 0
-	prevDelay := baseSleep
 
 	for {
-		if srv.Do() {
-			metrics.Record(time.Since(start), attempt)
+		allow, wait := retrier.Allow()
+		if !allow {
+			time.Sleep(wait)
+			continue
+		}
+
+		sec := int(time.Since(srv.start).Seconds())
+		metrics.BeginAttempt(sec)
+		attemptStart := time.Now()
+		resp := srv.Do()
+		latency := time.Since(attemptStart)
+		metrics.EndAttempt()
+
+		sleep, giveUp := retrier.Next(resp.Accepted, latency, resp.RetryAfter)
+		if resp.Accepted {
+			metrics.Record(time.Since(start), attempt, int(time.Since(srv.start).Seconds()))
+			return
+		}
+		if giveUp {
+			metrics.RecordGiveUp(attempt + 1)
 			return
 		}
-		delay := strategy(attempt, prevDelay)
-		prevDelay = delay
 		attempt++
-		time.Sleep(delay)
+		time.Sleep(sleep)
 	}
 }
 
-func runSimulation(numClients int, srv *Server, strategy Strategy) *Metrics {
+func runSimulation(numClients int, srv *Server, newRetrier func(clientIndex int) Retrier, arrival ArrivalProcess, metrics *Metrics) *Metrics {
 	var wg sync.WaitGroup
-	metrics := &Metrics{}
+
+	schedule := arrival.Schedule(numClients)
 
 	wg.Add(numClients)
 	for i := 0; i < numClients; i++ {
-		go func() {
+		delay := schedule[i]
+		go func(clientIndex int) {
 			defer wg.Done()
-			clientLoop(srv, strategy, metrics)
-		}()
+			if delay > 0 {
+				time.Sleep(delay)
+			}
+			clientLoop(srv, newRetrier(clientIndex), metrics)
+		}(i)
 	}
 	wg.Wait()
 	return metrics
@@ -192,20 +276,54 @@ func printHistogram(srv *Server) {
 			label = " DOWN"
 		}
 
-		fmt.Printf("  %3ds%s %s %d\n", s, label, bar, count)
+		suffix := fmt.Sprintf("%d", count)
+		if depth := srv.queueDepth[s]; depth > 0 {
+			suffix += fmt.Sprintf(" (queue=%d)", depth)
+		}
+		if reasons := srv.drops[s]; len(reasons) > 0 {
+			suffix += " drops:" + formatDropReasons(reasons)
+		}
+
+		fmt.Printf("  %3ds%s %s %s\n", s, label, bar, suffix)
 	}
 	fmt.Println()
 }
 
-func printSummary(srv *Server, metrics *Metrics) {
-	metrics.mu.Lock()
-	defer metrics.mu.Unlock()
-	srv.mu.Lock()
-	defer srv.mu.Unlock()
+func formatDropReasons(reasons map[DropReason]int) string {
+	parts := make([]string, 0, len(reasons))
+	for _, reason := range []DropReason{DropDown, DropCapacity, DropQueueFull} {
+		if n, ok := reasons[reason]; ok {
+			parts = append(parts, fmt.Sprintf("%s=%d", reason, n))
+		}
+	}
+	return strings.Join(parts, ",")
+}
 
-	// Time to stable: first second after downtime where no requests are rejected
-	recoveryTime := -1
+// summaryStats is the set of derived metrics used both by the single-run
+// summary and by -compare's side-by-side table, so the two don't drift.
+type summaryStats struct {
+	recoveryTime        int // seconds after the outage ends; -1 if not observed within the window
+	peakOvershoot       int
+	totalRequests       int
+	wastedReqs          int
+	clientsServed       int
+	clientsGivenUp      int
+	p50, p95, p99, p999 time.Duration
+}
+
+func (s summaryStats) wastedPct() float64 {
+	if s.totalRequests == 0 {
+		return 0
+	}
+	return float64(s.wastedReqs) / float64(s.totalRequests) * 100
+}
+
+// computeSummary derives summaryStats from a finished run. Caller must not
+// be holding srv.mu or metrics.mu.
+func computeSummary(srv *Server, metrics *Metrics) summaryStats {
+	srv.mu.Lock()
 	downSec := int(srv.downFor.Seconds())
+	recoveryTime := -1
 	for s := downSec; s < downSec+60; s++ {
 		rejected := srv.requests[s] - srv.accepted[s]
 		if srv.requests[s] > 0 && rejected == 0 {
@@ -213,8 +331,6 @@ func printSummary(srv *Server, metrics *Metrics) {
 			break
 		}
 	}
-
-	// Peak overshoot
 	peakOvershoot := 0
 	for s := downSec; s < downSec+60; s++ {
 		over := srv.requests[s] - srv.capacity
@@ -222,64 +338,202 @@ func printSummary(srv *Server, metrics *Metrics) {
 			peakOvershoot = over
 		}
 	}
+	srv.mu.Unlock()
 
-	// p99 latency
-	sort.Slice(metrics.latencies, func(i, j int) bool {
-		return metrics.latencies[i] < metrics.latencies[j]
-	})
-	var p99 time.Duration
-	if len(metrics.latencies) > 0 {
-		idx := int(float64(len(metrics.latencies)) * 0.99)
-		if idx >= len(metrics.latencies) {
-			idx = len(metrics.latencies) - 1
-		}
-		p99 = metrics.latencies[idx]
+	metrics.mu.Lock()
+	defer metrics.mu.Unlock()
+
+	return summaryStats{
+		recoveryTime:   recoveryTime,
+		peakOvershoot:  peakOvershoot,
+		totalRequests:  metrics.totalRequests,
+		wastedReqs:     metrics.wastedReqs,
+		clientsServed:  metrics.served,
+		clientsGivenUp: metrics.giveUps,
+		p50:            metrics.summary.Quantile(0.50),
+		p95:            metrics.summary.Quantile(0.95),
+		p99:            metrics.summary.Quantile(0.99),
+		p999:           metrics.summary.Quantile(0.999),
 	}
+}
+
+func printSummary(srv *Server, metrics *Metrics) {
+	stats := computeSummary(srv, metrics)
+
+	metrics.mu.Lock()
+	defer metrics.mu.Unlock()
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
 
 	fmt.Println("  Summary")
 	fmt.Println("  -------")
-	if recoveryTime >= 0 {
-		fmt.Printf("  Time to stable : %ds\n", recoveryTime)
+	if stats.recoveryTime >= 0 {
+		fmt.Printf("  Time to stable : %ds\n", stats.recoveryTime)
 	} else {
 		fmt.Printf("  Time to stable : >60s\n")
 	}
-	fmt.Printf("  Peak overshoot (reqs/s)    : %d over capacity\n", peakOvershoot)
-	fmt.Printf("  Total requests             : %d\n", metrics.totalRequests)
-	fmt.Printf("  Wasted (rejected) requests : %d\n", metrics.wastedReqs)
-	fmt.Printf("  Clients served             : %d\n", len(metrics.latencies))
-	fmt.Printf("  p99 client latency         : %s\n", p99.Round(time.Millisecond))
+	fmt.Printf("  Peak overshoot (reqs/s)    : %d over capacity\n", stats.peakOvershoot)
+	fmt.Printf("  Total requests             : %d\n", stats.totalRequests)
+	fmt.Printf("  Wasted (rejected) requests : %d\n", stats.wastedReqs)
+	fmt.Printf("  Clients served             : %d\n", stats.clientsServed)
+	fmt.Printf("  Clients given up           : %d\n", stats.clientsGivenUp)
+	fmt.Printf("  p99 client latency         : %s\n", stats.p99.Round(time.Millisecond))
+	if metrics.breakerTrips > 0 {
+		fmt.Printf("  Circuit breaker trips      : %d\n", metrics.breakerTrips)
+	}
+	if metrics.budgetDenials > 0 {
+		fmt.Printf("  AIMD budget denials        : %d\n", metrics.budgetDenials)
+	}
+	if len(metrics.limitSamples) > 0 {
+		fmt.Printf("  Adaptive concurrency limit : start=%d end=%d\n", metrics.limitSamples[0], metrics.limitSamples[len(metrics.limitSamples)-1])
+	}
+
+	dropTotals := map[DropReason]int{}
+	for _, reasons := range srv.drops {
+		for reason, n := range reasons {
+			dropTotals[reason] += n
+		}
+	}
+	if len(dropTotals) > 0 {
+		fmt.Printf("  Drop reasons               : %s\n", formatDropReasons(dropTotals))
+	}
 	fmt.Println()
 }
 
-func main() {
-	strategyName := flag.String("strategy", "constant", "retry strategy: constant|backoff|jitter|decorrelated")
-	flag.Parse()
+// printLatencyHistogram prints the non-empty buckets of the simulation's
+// latency histogram, giving a coarse view of the distribution's shape
+// alongside the quantiles in printSummary.
+func printLatencyHistogram(metrics *Metrics) {
+	bounds, counts := metrics.histogram.snapshot()
 
-	strategies := map[string]Strategy{
-		"constant":     constantRetry,
-		"backoff":      exponentialBackoff,
-		"jitter":       fullJitter,
-		"decorrelated": decorrelatedJitter,
+	var total uint64
+	for _, c := range counts {
+		total += c
 	}
-
-	strat, ok := strategies[*strategyName]
-	if !ok {
-		fmt.Printf("Unknown strategy %q. Use: constant, backoff, jitter, decorrelated\n", *strategyName)
+	if total == 0 {
 		return
 	}
 
+	fmt.Println("  Latency histogram")
+	fmt.Println("  -----------------")
+	for i, count := range counts {
+		if count == 0 {
+			continue
+		}
+		var label string
+		if i == len(bounds) {
+			label = fmt.Sprintf("> %s", bounds[len(bounds)-1].Round(time.Millisecond))
+		} else {
+			label = fmt.Sprintf("<=%s", bounds[i].Round(time.Millisecond))
+		}
+		fmt.Printf("  %-12s %d\n", label, count)
+	}
+	fmt.Println()
+}
+
+func main() {
+	strategyName := flag.String("strategy", "constant", "retry strategy: constant|backoff|jitter|decorrelated|breaker|aimd|adaptive-concurrency|server-hinted")
+	arrivalName := flag.String("arrival", "simultaneous", "client arrival process: simultaneous|poisson|uniform|zipfian|bursty")
+	rate := flag.Float64("rate", 500, "average client arrival rate in clients/sec (poisson, uniform, zipfian, bursty)")
+	zipfS := flag.Float64("zipf-s", 1.5, "Zipf distribution s parameter (zipfian arrival, must be > 1)")
+	zipfV := flag.Float64("zipf-v", 1.0, "Zipf distribution v parameter (zipfian arrival, must be >= 1)")
+	burstOn := flag.Duration("burst-on", 2*time.Second, "duration of an arrival burst (bursty arrival)")
+	burstOff := flag.Duration("burst-off", 3*time.Second, "quiet duration between bursts (bursty arrival)")
+	serverModelName := flag.String("server-model", "hardcap", "server admission model: hardcap|tokenbucket|leaky|queue|degraded")
+	tbBurst := flag.Int("tb-burst", 200, "token bucket burst size (tokenbucket server model)")
+	queueCap := flag.Int("queue-cap", 100, "max requests waiting in line beyond capacity (leaky, queue server models)")
+	degradedSoft := flag.Int("degraded-soft", 150, "requests/sec above which latency starts to degrade (degraded server model)")
+	degradedLatencyPerOverload := flag.Duration("degraded-latency-per-overload", 20*time.Millisecond, "extra latency added per request/sec over the soft threshold (degraded server model)")
+	breakerThreshold := flag.Int("breaker-threshold", 5, "consecutive failures before a circuit breaker trips open (breaker strategy)")
+	breakerOpenFor := flag.Duration("breaker-open-for", 2*time.Second, "how long a tripped circuit breaker stays open before probing again (breaker strategy)")
+	aimdMaxBudget := flag.Float64("aimd-max-budget", 10, "max retry budget tokens (aimd strategy)")
+	aimdAdditive := flag.Float64("aimd-additive", 1, "tokens added to the retry budget per success (aimd strategy)")
+	aimdMultiplicative := flag.Float64("aimd-multiplicative", 0.5, "factor the retry budget is multiplied by on rejection (aimd strategy)")
+	concurrencyInitialLimit := flag.Float64("concurrency-initial-limit", 20, "initial in-flight limit (adaptive-concurrency strategy)")
+	concurrencyMinLimit := flag.Float64("concurrency-min-limit", 1, "minimum in-flight limit (adaptive-concurrency strategy)")
+	concurrencyMaxLimit := flag.Float64("concurrency-max-limit", 500, "maximum in-flight limit (adaptive-concurrency strategy)")
+	seed := flag.Int64("seed", 0, "RNG seed for reproducible arrival schedules and per-client retry jitter; 0 picks a random seed and reports it. Wall-clock timing (request bucketing, measured latency) still varies run to run, so results are close but not bit-identical across repeats")
+	compare := flag.Bool("compare", false, "run every strategy in -compare-strategies against the same seeded scenario and print a side-by-side report")
+	compareStrategies := flag.String("compare-strategies", "constant,backoff,jitter,decorrelated", "comma-separated strategies to run in -compare mode")
+	format := flag.String("format", "table", "-compare report format: table|json|csv|prom")
+	metricsOut := flag.String("metrics-out", "", "write a per-second metrics time series (requests, accepted, rejected, in-flight, latency quantiles) to this file; extension selects .csv or .prom (single-run mode only)")
+	flag.Parse()
+
+	const numClients = 1000
 	const (
-		numClients     = 1000
 		serverCapacity = 200
 		downDuration   = 10 * time.Second
 	)
 
-	fmt.Printf("  Strategy: %s | Clients: %d | Server capacity: %d req/s | Outage: %s\n",
-		*strategyName, numClients, serverCapacity, downDuration)
+	resolvedSeed := *seed
+	if resolvedSeed == 0 {
+		resolvedSeed = time.Now().UnixNano()
+	}
+
+	retrierP := retrierParams{
+		breakerThreshold:        *breakerThreshold,
+		breakerOpenFor:          *breakerOpenFor,
+		aimdMaxBudget:           *aimdMaxBudget,
+		aimdAdditive:            *aimdAdditive,
+		aimdMultiplicative:      *aimdMultiplicative,
+		concurrencyInitialLimit: *concurrencyInitialLimit,
+		concurrencyMinLimit:     *concurrencyMinLimit,
+		concurrencyMaxLimit:     *concurrencyMaxLimit,
+	}
+	cfg := simConfig{
+		numClients:     numClients,
+		serverCapacity: serverCapacity,
+		downDuration:   downDuration,
+		seed:           resolvedSeed,
+		buildArrival: func() (ArrivalProcess, error) {
+			return buildArrivalProcess(*arrivalName, *rate, *zipfS, *zipfV, *burstOn, *burstOff, numClients)
+		},
+		buildModel: func() (serverModel, error) {
+			return buildServerModel(*serverModelName, serverCapacity, *tbBurst, *queueCap, *degradedSoft, *degradedLatencyPerOverload)
+		},
+	}
+
+	if *compare {
+		names := strings.Split(*compareStrategies, ",")
+		for i := range names {
+			names[i] = strings.TrimSpace(names[i])
+		}
 
-	srv := NewServer(serverCapacity, downDuration)
-	metrics := runSimulation(numClients, srv, strat)
+		// The report itself (table/json/csv/prom) is the only thing that
+		// belongs on stdout here - writeCompareReport's non-table formats are
+		// meant for downstream parsing (jq, a CSV loader, a Prometheus
+		// scrape), and a banner or error line ahead of it would corrupt that
+		// output. So this goes to stderr regardless of -format.
+		fmt.Fprintf(os.Stderr, "  Compare: %s | Arrival: %s | Server model: %s | Clients: %d | Server capacity: %d req/s | Outage: %s | Seed: %d\n",
+			strings.Join(names, ","), *arrivalName, *serverModelName, numClients, serverCapacity, downDuration, resolvedSeed)
+
+		results, err := runCompare(names, cfg, retrierP)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return
+		}
+		if err := writeCompareReport(*format, results); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+		}
+		return
+	}
+
+	fmt.Printf("  Strategy: %s | Arrival: %s | Server model: %s | Clients: %d | Server capacity: %d req/s | Outage: %s | Seed: %d\n",
+		*strategyName, *arrivalName, *serverModelName, numClients, serverCapacity, downDuration, resolvedSeed)
+
+	srv, metrics, err := runOne(*strategyName, cfg, retrierP)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
 
 	printHistogram(srv)
 	printSummary(srv, metrics)
+	printLatencyHistogram(metrics)
+
+	if *metricsOut != "" {
+		if err := writeMetricsTimeSeries(*metricsOut, srv, metrics); err != nil {
+			fmt.Println(err)
+		}
+	}
 }
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// writeMetricsTimeSeries dumps a per-second metrics time series to path,
+// choosing the format from its extension. It's meant for feeding a
+// longer-running simulation's behavior into an external dashboard, rather
+// than the at-a-glance printSummary/printHistogram console output.
+func writeMetricsTimeSeries(path string, srv *Server, metrics *Metrics) error {
+	switch filepath.Ext(path) {
+	case ".csv":
+		return writeMetricsCSV(path, srv, metrics)
+	case ".prom":
+		return writeMetricsProm(path, srv, metrics)
+	default:
+		return fmt.Errorf("unsupported -metrics-out extension %q. Use a .csv or .prom file", filepath.Ext(path))
+	}
+}
+
+// simulatedSeconds returns every simulated second from 0 up to the last
+// second in which the server saw a request, in ascending order, including
+// seconds in between that saw no requests.
+func simulatedSeconds(srv *Server) []int {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	maxSec := 0
+	for s := range srv.requests {
+		if s > maxSec {
+			maxSec = s
+		}
+	}
+	seconds := make([]int, maxSec+1)
+	for i := range seconds {
+		seconds[i] = i
+	}
+	return seconds
+}
+
+func writeMetricsCSV(path string, srv *Server, metrics *Metrics) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	w := csv.NewWriter(f)
+	defer w.Flush()
+
+	header := []string{"second", "requests", "accepted", "rejected", "max_in_flight", "p50_ms", "p95_ms", "p99_ms", "p999_ms"}
+	if err := w.Write(header); err != nil {
+		return err
+	}
+
+	for _, sec := range simulatedSeconds(srv) {
+		srv.mu.Lock()
+		requests := srv.requests[sec]
+		accepted := srv.accepted[sec]
+		srv.mu.Unlock()
+
+		metrics.mu.Lock()
+		maxInFlight := metrics.maxInFlight[sec]
+		summary := metrics.perSecond[sec]
+		metrics.mu.Unlock()
+
+		row := []string{
+			fmt.Sprintf("%d", sec),
+			fmt.Sprintf("%d", requests),
+			fmt.Sprintf("%d", accepted),
+			fmt.Sprintf("%d", requests-accepted),
+			fmt.Sprintf("%d", maxInFlight),
+			msString(summary, 0.5),
+			msString(summary, 0.95),
+			msString(summary, 0.99),
+			msString(summary, 0.999),
+		}
+		if err := w.Write(row); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func msString(s *QuantileSummary, q float64) string {
+	if s == nil {
+		return "0.00"
+	}
+	return fmt.Sprintf("%.2f", s.Quantile(q).Seconds()*1000)
+}
+
+func writeMetricsProm(path string, srv *Server, metrics *Metrics) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	seconds := simulatedSeconds(srv)
+
+	fmt.Fprintln(f, "# HELP retry_sim_requests_total Requests seen in a given simulated second.")
+	fmt.Fprintln(f, "# TYPE retry_sim_requests_total gauge")
+	for _, sec := range seconds {
+		srv.mu.Lock()
+		requests := srv.requests[sec]
+		srv.mu.Unlock()
+		fmt.Fprintf(f, "retry_sim_requests_total{second=\"%d\"} %d\n", sec, requests)
+	}
+
+	fmt.Fprintln(f, "# HELP retry_sim_accepted_total Accepted requests in a given simulated second.")
+	fmt.Fprintln(f, "# TYPE retry_sim_accepted_total gauge")
+	for _, sec := range seconds {
+		srv.mu.Lock()
+		accepted := srv.accepted[sec]
+		srv.mu.Unlock()
+		fmt.Fprintf(f, "retry_sim_accepted_total{second=\"%d\"} %d\n", sec, accepted)
+	}
+
+	fmt.Fprintln(f, "# HELP retry_sim_in_flight Peak concurrent in-flight attempts observed in a given simulated second.")
+	fmt.Fprintln(f, "# TYPE retry_sim_in_flight gauge")
+	for _, sec := range seconds {
+		metrics.mu.Lock()
+		maxInFlight := metrics.maxInFlight[sec]
+		metrics.mu.Unlock()
+		fmt.Fprintf(f, "retry_sim_in_flight{second=\"%d\"} %d\n", sec, maxInFlight)
+	}
+
+	fmt.Fprintln(f, "# HELP retry_sim_latency_seconds Client latency quantiles among requests completed in a given simulated second.")
+	fmt.Fprintln(f, "# TYPE retry_sim_latency_seconds summary")
+	for _, sec := range seconds {
+		metrics.mu.Lock()
+		summary := metrics.perSecond[sec]
+		metrics.mu.Unlock()
+		if summary == nil {
+			continue
+		}
+		for _, q := range quantileObjectives {
+			fmt.Fprintf(f, "retry_sim_latency_seconds{second=\"%d\",quantile=\"%g\"} %.6f\n", sec, q, summary.Quantile(q).Seconds())
+		}
+	}
+	return nil
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"math/rand"
+	"time"
+)
+
+// serverHintedRetrier honors the server's Retry-After hint when present,
+// adding jitter so every client waiting on the same hint doesn't retry in
+// lockstep, and falls back to decorrelated jitter when a rejection carries
+// no hint (a zero RetryAfter).
+type serverHintedRetrier struct {
+	fallback  Strategy
+	rng       *rand.Rand
+	attempt   int
+	prevDelay time.Duration
+}
+
+func newServerHintedRetrier(rng *rand.Rand) *serverHintedRetrier {
+	return &serverHintedRetrier{fallback: decorrelatedJitter, rng: rng, prevDelay: baseSleep}
+}
+
+func (r *serverHintedRetrier) Allow() (bool, time.Duration) {
+	return true, 0
+}
+
+func (r *serverHintedRetrier) Next(ok bool, _, retryAfter time.Duration) (time.Duration, bool) {
+	if ok {
+		return 0, false
+	}
+	r.attempt++
+	if retryAfter > 0 {
+		delay := jitterAround(r.rng, retryAfter)
+		r.prevDelay = delay
+		return delay, false
+	}
+	delay := r.fallback(r.rng, r.attempt, r.prevDelay)
+	r.prevDelay = delay
+	return delay, false
+}
+
+// jitterAround applies equal jitter around a server-hinted delay: half the
+// hint is guaranteed, and the other half is randomized, so clients that
+// received the same hint don't all retry at exactly the same instant.
+func jitterAround(rng *rand.Rand, hint time.Duration) time.Duration {
+	half := hint / 2
+	return half + time.Duration(rng.Int63n(int64(half)+1))
+}
@@ -0,0 +1,341 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// DropReason explains why a request was not accepted, so the histogram and
+// summary can distinguish a hard outage from ordinary capacity pressure.
+type DropReason string
+
+const (
+	DropNone      DropReason = ""
+	DropDown      DropReason = "down"
+	DropCapacity  DropReason = "capacity"
+	DropQueueFull DropReason = "queue_full"
+)
+
+// Response is what a client learns from one attempt: whether it was
+// accepted, and when rejected, how long the server suggests waiting before
+// trying again (its Retry-After hint) and why the request was rejected.
+type Response struct {
+	Accepted   bool
+	RetryAfter time.Duration
+	Reason     DropReason
+}
+
+// attemptResult is a serverModel's internal verdict on one attempt, before
+// Server turns it into a Response and records bookkeeping.
+type attemptResult struct {
+	accepted     bool
+	extraLatency time.Duration
+	reason       DropReason
+	retryAfter   time.Duration
+}
+
+// serverModel implements one way of deciding whether a request is accepted
+// once the server is up, how much extra latency (e.g. queueing delay) the
+// caller should observe before the outcome is known, and - when rejecting -
+// how long it expects to stay that way.
+type serverModel interface {
+	attempt(s *Server, elapsed time.Duration) attemptResult
+}
+
+// nextSecondBoundary returns how long until the per-second admission
+// windows (requests/accepted maps) roll over, used as a Retry-After hint by
+// models that cap throughput per second.
+func nextSecondBoundary(elapsed time.Duration) time.Duration {
+	return time.Second - elapsed%time.Second
+}
+
+// Server simulates a service with limited capacity. The admission behavior
+// itself is delegated to a pluggable serverModel; Server owns the
+// bookkeeping (per-second request/accept counts, drop reasons, queue depth)
+// shared by every model.
+type Server struct {
+	mu         sync.Mutex
+	capacity   int // nominal capacity, used for the histogram's capacity marker
+	downFor    time.Duration
+	start      time.Time
+	requests   map[int]int               // second -> request count
+	accepted   map[int]int                // second -> accepted count
+	drops      map[int]map[DropReason]int // second -> reason -> count
+	queueDepth map[int]int                // second -> max observed queue depth
+
+	model serverModel
+}
+
+func NewServer(capacity int, downFor time.Duration) *Server {
+	return &Server{
+		capacity:   capacity,
+		downFor:    downFor,
+		start:      time.Now(),
+		requests:   make(map[int]int),
+		accepted:   make(map[int]int),
+		drops:      make(map[int]map[DropReason]int),
+		queueDepth: make(map[int]int),
+		model:      hardCapModel{capacity: capacity},
+	}
+}
+
+// Do attempts a request, returning the server's full Response including any
+// Retry-After hint.
+func (s *Server) Do() Response {
+	elapsed := time.Since(s.start)
+	sec := int(elapsed.Seconds())
+
+	s.mu.Lock()
+	s.requests[sec]++
+	s.mu.Unlock()
+
+	if elapsed < s.downFor {
+		s.recordDrop(sec, DropDown)
+		return Response{Accepted: false, RetryAfter: s.downFor - elapsed, Reason: DropDown}
+	}
+
+	result := s.model.attempt(s, elapsed)
+	if result.extraLatency > 0 {
+		time.Sleep(result.extraLatency)
+	}
+	if !result.accepted {
+		s.recordDrop(sec, result.reason)
+		return Response{Accepted: false, RetryAfter: result.retryAfter, Reason: result.reason}
+	}
+	return Response{Accepted: true}
+}
+
+func (s *Server) recordDrop(sec int, reason DropReason) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.drops[sec] == nil {
+		s.drops[sec] = make(map[DropReason]int)
+	}
+	s.drops[sec][reason]++
+}
+
+func (s *Server) recordQueueDepth(sec, depth int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if depth > s.queueDepth[sec] {
+		s.queueDepth[sec] = depth
+	}
+}
+
+// acceptedCount returns how many requests have already been accepted this
+// second.
+func (s *Server) acceptedCount(sec int) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.accepted[sec]
+}
+
+// recordAccept counts an accepted request without any admission check, for
+// models (token bucket, leaky bucket, queue) that enforce capacity on their
+// own terms rather than a flat per-second count.
+func (s *Server) recordAccept(sec int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.accepted[sec]++
+}
+
+// tryAcceptUnderCap atomically checks-and-increments the per-second accepted
+// count against capacity, returning the load observed before admission.
+func (s *Server) tryAcceptUnderCap(sec, capacity int) (accepted bool, load int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	load = s.accepted[sec]
+	if load >= capacity {
+		return false, load
+	}
+	s.accepted[sec]++
+	return true, load
+}
+
+// hardCapModel is the simulator's original behavior: a hard per-second cap
+// that instantly rejects overflow.
+type hardCapModel struct {
+	capacity int
+}
+
+func (m hardCapModel) attempt(s *Server, elapsed time.Duration) attemptResult {
+	sec := int(elapsed.Seconds())
+	accepted, _ := s.tryAcceptUnderCap(sec, m.capacity)
+	if !accepted {
+		return attemptResult{reason: DropCapacity, retryAfter: nextSecondBoundary(elapsed)}
+	}
+	return attemptResult{accepted: true}
+}
+
+// tokenBucketModel admits a request only if a token is available, refilling
+// continuously at Rate tokens/sec up to Burst tokens.
+type tokenBucketModel struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func newTokenBucketModel(rate float64, burst int) *tokenBucketModel {
+	return &tokenBucketModel{
+		rate:   rate,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+	}
+}
+
+func (m *tokenBucketModel) attempt(s *Server, elapsed time.Duration) attemptResult {
+	sec := int(elapsed.Seconds())
+
+	m.mu.Lock()
+	now := time.Now()
+	m.tokens += now.Sub(m.last).Seconds() * m.rate
+	if m.tokens > m.burst {
+		m.tokens = m.burst
+	}
+	m.last = now
+
+	if m.tokens < 1 {
+		wait := time.Duration((1 - m.tokens) / m.rate * float64(time.Second))
+		m.mu.Unlock()
+		return attemptResult{reason: DropCapacity, retryAfter: wait}
+	}
+	m.tokens--
+	m.mu.Unlock()
+
+	s.recordAccept(sec)
+	return attemptResult{accepted: true}
+}
+
+// leakyBucketModel models a classic leaky bucket: the bucket drains at a
+// constant Rate, and a request is queued (adding latency) as long as the
+// bucket isn't full, otherwise rejected.
+type leakyBucketModel struct {
+	mu       sync.Mutex
+	rate     float64 // requests/sec drained
+	capacity float64 // bucket capacity ("water" level at which it overflows)
+	level    float64
+	last     time.Time
+}
+
+func newLeakyBucketModel(rate float64, capacity int) *leakyBucketModel {
+	return &leakyBucketModel{rate: rate, capacity: float64(capacity), last: time.Now()}
+}
+
+func (m *leakyBucketModel) attempt(s *Server, elapsed time.Duration) attemptResult {
+	sec := int(elapsed.Seconds())
+
+	m.mu.Lock()
+	now := time.Now()
+	m.level -= now.Sub(m.last).Seconds() * m.rate
+	if m.level < 0 {
+		m.level = 0
+	}
+	m.last = now
+
+	if m.level >= m.capacity {
+		retryAfter := time.Duration((m.level-m.capacity+1)/m.rate*float64(time.Second))
+		m.mu.Unlock()
+		return attemptResult{reason: DropQueueFull, retryAfter: retryAfter}
+	}
+	wait := m.level / m.rate
+	m.level++
+	depth := int(m.level)
+	m.mu.Unlock()
+
+	s.recordQueueDepth(sec, depth)
+	s.recordAccept(sec)
+	return attemptResult{accepted: true, extraLatency: time.Duration(wait * float64(time.Second))}
+}
+
+// queueModel is a bounded FIFO: Capacity requests/sec are served directly,
+// up to QueueCap more may wait their turn, and the rest are rejected. A
+// queued request's latency is proportional to its position in line.
+type queueModel struct {
+	mu       sync.Mutex
+	capacity int
+	queueCap int
+	depth    int
+}
+
+func newQueueModel(capacity, queueCap int) *queueModel {
+	return &queueModel{capacity: capacity, queueCap: queueCap}
+}
+
+func (m *queueModel) attempt(s *Server, elapsed time.Duration) attemptResult {
+	sec := int(elapsed.Seconds())
+
+	m.mu.Lock()
+	if m.depth >= m.capacity+m.queueCap {
+		m.mu.Unlock()
+		return attemptResult{reason: DropQueueFull, retryAfter: time.Duration(float64(time.Second) / float64(m.capacity))}
+	}
+	m.depth++
+	position := m.depth
+	m.mu.Unlock()
+
+	s.recordQueueDepth(sec, position)
+
+	wait := time.Duration(float64(position) / float64(m.capacity) * float64(time.Second))
+
+	// The queue slot claimed above must stay held for the simulated service
+	// time, not just until this call returns - Server.Do() sleeps for
+	// extraLatency after attempt() returns, so releasing depth here (e.g. via
+	// a deferred decrement) would free the slot before that sleep even
+	// starts. Releasing it on its own timer keeps depth an honest count of
+	// concurrently in-queue requests regardless of when the caller sleeps.
+	time.AfterFunc(wait, func() {
+		m.mu.Lock()
+		m.depth--
+		m.mu.Unlock()
+	})
+
+	s.recordAccept(sec)
+	return attemptResult{accepted: true, extraLatency: wait}
+}
+
+// degradedModel serves everything up to SoftCapacity req/s at full speed;
+// beyond that, latency grows proportionally to the overload until
+// HardCapacity is reached, at which point requests are rejected outright.
+type degradedModel struct {
+	softCapacity       int
+	hardCapacity       int
+	latencyPerOverload time.Duration
+}
+
+func (m degradedModel) attempt(s *Server, elapsed time.Duration) attemptResult {
+	sec := int(elapsed.Seconds())
+	accepted, load := s.tryAcceptUnderCap(sec, m.hardCapacity)
+	if !accepted {
+		return attemptResult{reason: DropCapacity, retryAfter: nextSecondBoundary(elapsed)}
+	}
+
+	var extra time.Duration
+	if load > m.softCapacity {
+		extra = time.Duration(load-m.softCapacity) * m.latencyPerOverload
+	}
+	return attemptResult{accepted: true, extraLatency: extra}
+}
+
+// buildServerModel constructs the serverModel named by flag value name. The
+// numeric parameters are sized off the server's nominal capacity so a model
+// switch is a fair comparison at the same target throughput.
+func buildServerModel(name string, capacity, tbBurst, queueCap, degradedSoft int, degradedLatencyPerOverload time.Duration) (serverModel, error) {
+	switch name {
+	case "hardcap":
+		return hardCapModel{capacity: capacity}, nil
+	case "tokenbucket":
+		return newTokenBucketModel(float64(capacity), tbBurst), nil
+	case "leaky":
+		return newLeakyBucketModel(float64(capacity), queueCap), nil
+	case "queue":
+		return newQueueModel(capacity, queueCap), nil
+	case "degraded":
+		return degradedModel{softCapacity: degradedSoft, hardCapacity: capacity, latencyPerOverload: degradedLatencyPerOverload}, nil
+	default:
+		return nil, fmt.Errorf("unknown server model %q. Use: hardcap, tokenbucket, leaky, queue, degraded", name)
+	}
+}